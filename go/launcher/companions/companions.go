@@ -0,0 +1,119 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package companions starts and stops sidecar processes and polls for the
+// ready files they write once they are available for use.
+package companions
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often WaitReady checks for a ready file to appear.
+const pollInterval = 50 * time.Millisecond
+
+// Spec describes a single companion binary to launch: its executable path
+// and the ready file it will write once it is ready to be used. ReadyFile
+// is communicated to the companion process via the ENV_READY_FILE
+// environment variable.
+type Spec struct {
+	Exe       string
+	ReadyFile string
+}
+
+// Companion is a started companion process.
+type Companion struct {
+	Spec Spec
+	Cmd  *exec.Cmd
+}
+
+// Start launches every companion described by specs, in order, and returns
+// the started processes. If any companion fails to start, previously
+// started companions are killed before returning the error.
+func Start(specs []Spec) ([]*Companion, error) {
+	var started []*Companion
+
+	for _, spec := range specs {
+		cmd := exec.Command(spec.Exe)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		cmd.Env = append(os.Environ(), "ENV_READY_FILE="+spec.ReadyFile)
+		if err := cmd.Start(); err != nil {
+			Stop(started)
+			return nil, fmt.Errorf("companions: unable to start %q: %v", spec.Exe, err)
+		}
+		started = append(started, &Companion{Spec: spec, Cmd: cmd})
+	}
+	return started, nil
+}
+
+// WaitReady blocks until every companion has written its ready file, or
+// until timeout elapses.
+func WaitReady(companions []*Companion, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, c := range companions {
+		for {
+			if _, err := os.Stat(c.Spec.ReadyFile); err == nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("companions: timed out waiting for %q to become ready", c.Spec.Exe)
+			}
+			time.Sleep(pollInterval)
+		}
+	}
+	return nil
+}
+
+// Stop kills every companion process, ignoring processes that have already
+// exited.
+func Stop(companions []*Companion) {
+	for _, c := range companions {
+		c.Cmd.Process.Kill()
+		c.Cmd.Wait()
+	}
+}
+
+// WriteReadyFile atomically creates path with the given contents by writing
+// to a temp file in the same directory and renaming it into place, so
+// readers never observe a partially written file.
+func WriteReadyFile(path, contents string) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, ".ready-")
+	if err != nil {
+		return fmt.Errorf("companions: unable to create temp ready file: %v", err)
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.WriteString(contents); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("companions: unable to write ready file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("companions: unable to close ready file: %v", err)
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("companions: unable to rename ready file into place: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package companions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMain re-execs this test binary as a fake companion process when
+// invoked with GO_WANT_FAKE_COMPANION=1, instead of running the test
+// suite. This lets TestStartTellsChildWhereToWriteItsReadyFile launch a
+// real child process without needing a separate helper binary on disk.
+func TestMain(m *testing.M) {
+	if os.Getenv("GO_WANT_FAKE_COMPANION") == "1" {
+		fakeCompanionMain()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeCompanionMain writes a ready file at the path named by the
+// ENV_READY_FILE environment variable, mirroring how a real companion
+// binary is expected to behave once it has finished starting up.
+func fakeCompanionMain() {
+	path := os.Getenv("ENV_READY_FILE")
+	if path == "" {
+		os.Exit(1)
+	}
+	if err := WriteReadyFile(path, "ready"); err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestWriteReadyFileIsReadableAfterwards(t *testing.T) {
+	dir, err := ioutil.TempDir("", "companions")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ready")
+	if err := WriteReadyFile(path, "http://127.0.0.1:1234"); err != nil {
+		t.Fatalf("WriteReadyFile() returned error: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	if string(got) != "http://127.0.0.1:1234" {
+		t.Errorf("ready file contents = %q, want %q", got, "http://127.0.0.1:1234")
+	}
+
+	// No temp files should be left behind alongside the renamed file.
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() returned error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want 1 (just the ready file)", len(entries))
+	}
+}
+
+func TestWaitReadySucceedsOnceFileExists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "companions")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ready")
+	if err := WriteReadyFile(path, "ready"); err != nil {
+		t.Fatalf("WriteReadyFile() returned error: %v", err)
+	}
+
+	companions := []*Companion{{Spec: Spec{ReadyFile: path}}}
+	if err := WaitReady(companions, time.Second); err != nil {
+		t.Errorf("WaitReady() returned error: %v", err)
+	}
+}
+
+func TestWaitReadyTimesOutWhenFileNeverAppears(t *testing.T) {
+	dir, err := ioutil.TempDir("", "companions")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	companions := []*Companion{{Spec: Spec{ReadyFile: filepath.Join(dir, "never")}}}
+	if err := WaitReady(companions, 100*time.Millisecond); err == nil {
+		t.Errorf("WaitReady() returned nil error, want timeout error")
+	}
+}
+
+// TestStartTellsChildWhereToWriteItsReadyFile exercises Start and WaitReady
+// together against a real child process, rather than calling WriteReadyFile
+// directly, so it would catch a regression where Start forgets to tell the
+// companion which ready file it is responsible for.
+func TestStartTellsChildWhereToWriteItsReadyFile(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("Executable() returned error: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "companions")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	readyFile := filepath.Join(dir, "ready")
+	spec := Spec{Exe: exe, ReadyFile: readyFile}
+
+	// Start inherits os.Environ() for the child, so setting this here makes
+	// the re-exec'd test binary run fakeCompanionMain instead of the test
+	// suite; see TestMain. The child only writes readyFile if Start handed
+	// it the path via ENV_READY_FILE.
+	os.Setenv("GO_WANT_FAKE_COMPANION", "1")
+	defer os.Unsetenv("GO_WANT_FAKE_COMPANION")
+
+	started, err := Start([]Spec{spec})
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	defer Stop(started)
+
+	if err := WaitReady(started, 5*time.Second); err != nil {
+		t.Fatalf("WaitReady() returned error: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(readyFile)
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	if string(got) != "ready" {
+		t.Errorf("ready file contents = %q, want %q", got, "ready")
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package steplog emits a flushable, machine-readable JSON stream of
+// launcher lifecycle events, one per line, so a CI task driver can tail the
+// file live. Logger offers only Step, Event and Close; it is not a
+// diagnostics.Diagnostics implementation, and events reported through a
+// diagnostics.Diagnostics value do not reach the step log.
+package steplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the version of the Event schema written by this
+// package. Bump it whenever a field is added, removed, or reinterpreted.
+const SchemaVersion = 1
+
+// Event is a single entry in the step log.
+type Event struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	StepID        string        `json:"stepId"`
+	Timestamp     time.Time     `json:"timestamp"`
+	Duration      time.Duration `json:"duration,omitempty"`
+	Message       string        `json:"message,omitempty"`
+}
+
+// Logger writes Events as newline-delimited JSON to a file, flushing after
+// every write so tailers see events as they happen.
+type Logger struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// New creates a Logger that writes to path, truncating any existing
+// contents.
+func New(path string) (*Logger, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("steplog: unable to create %s: %v", path, err)
+	}
+	return &Logger{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Step begins a new step and returns a function that ends it, logging an
+// event carrying the elapsed duration and message.
+func (l *Logger) Step(stepID, message string) func() {
+	start := time.Now()
+	l.write(Event{StepID: stepID, Timestamp: start, Message: message})
+	return func() {
+		l.write(Event{StepID: stepID, Timestamp: time.Now(), Duration: time.Since(start), Message: message + " done"})
+	}
+}
+
+// Event logs a standalone, instantaneous event.
+func (l *Logger) Event(stepID, message string) {
+	l.write(Event{StepID: stepID, Timestamp: time.Now(), Message: message})
+}
+
+func (l *Logger) write(e Event) {
+	e.SchemaVersion = SchemaVersion
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(e); err != nil {
+		fmt.Fprintf(os.Stderr, "steplog: unable to write event: %v\n", err)
+		return
+	}
+	l.file.Sync()
+}
+
+// Close flushes and closes the underlying file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
@@ -0,0 +1,104 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package steplog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Unmarshal() returned error: %v", err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scanner error: %v", err)
+	}
+	return events
+}
+
+func TestEventWritesSchemaVersionedEntry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steplog")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "steps.json")
+	l, err := New(path)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	defer l.Close()
+
+	l.Event("proxy_start", "proxy listening at 127.0.0.1:1234")
+
+	events := readEvents(t, path)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].SchemaVersion != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", events[0].SchemaVersion, SchemaVersion)
+	}
+	if events[0].StepID != "proxy_start" {
+		t.Errorf("StepID = %q, want %q", events[0].StepID, "proxy_start")
+	}
+}
+
+func TestStepWritesStartAndEndEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "steplog")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "steps.json")
+	l, err := New(path)
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	defer l.Close()
+
+	end := l.Step("env_setup", "environment setup")
+	end()
+
+	events := readEvents(t, path)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if events[0].StepID != "env_setup" || events[1].StepID != "env_setup" {
+		t.Errorf("events = %+v, want both entries to carry StepID %q", events, "env_setup")
+	}
+	if events[1].Duration <= 0 {
+		t.Errorf("end event Duration = %v, want > 0", events[1].Duration)
+	}
+}
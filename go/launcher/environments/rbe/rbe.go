@@ -0,0 +1,101 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbe provides an environment.Env that reserves a browser worker
+// from a Remote Build Execution style grid instead of starting a local
+// browser process.
+package rbe
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/bazelbuild/rules_webtesting/go/launcher/diagnostics"
+	"github.com/bazelbuild/rules_webtesting/go/launcher/environments/environment"
+	"github.com/bazelbuild/rules_webtesting/go/metadata/metadata"
+)
+
+// Flags configuring the RBE environment. These are plain launcher flags,
+// not metadata fields, since the metadata.Metadata surface is not under
+// this package's control.
+var (
+	endpointFlag   = flag.String("rbe_endpoint", "", "address of the RBE scheduler to reserve a browser worker from; required when the metadata selects the rbe environment")
+	credentialFlag = flag.String("rbe_credential_file", "", "path to a service account key JSON file used to authenticate to the RBE scheduler; if unset, requests are sent unauthenticated")
+	platformFlag   = flag.String("rbe_platform", "", "platform constraints to request from the RBE scheduler when reserving a worker")
+)
+
+const dialTimeout = 30 * time.Second
+
+// env reserves a remote browser worker for the life of a single test.
+type env struct {
+	d        diagnostics.Diagnostics
+	client   *schedulerClient
+	worker   *worker
+	endpoint string
+	platform string
+}
+
+// New creates a new RBE environment. m is unused beyond satisfying the
+// envProvider signature expected by launcher.RegisterEnvProviderFunc;
+// configuration comes from this package's own flags.
+func New(m *metadata.Metadata, d diagnostics.Diagnostics) (environment.Env, error) {
+	if *endpointFlag == "" {
+		return nil, fmt.Errorf("rbe: --rbe_endpoint is required")
+	}
+
+	client, err := newSchedulerClient(*endpointFlag, *credentialFlag)
+	if err != nil {
+		return nil, fmt.Errorf("rbe: unable to create scheduler client for %q: %v", *endpointFlag, err)
+	}
+
+	return &env{
+		d:        d,
+		client:   client,
+		endpoint: *endpointFlag,
+		platform: *platformFlag,
+	}, nil
+}
+
+// SetUp dials the remote scheduler and reserves a browser worker matching
+// --rbe_platform, if set.
+func (e *env) SetUp(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	w, err := e.client.Reserve(ctx, e.platform)
+	if err != nil {
+		return fmt.Errorf("rbe: unable to reserve a worker from %q: %v", e.endpoint, err)
+	}
+	e.worker = w
+	return nil
+}
+
+// WDAddress returns the WebDriver endpoint of the reserved worker.
+func (e *env) WDAddress(ctx context.Context) (*url.URL, error) {
+	if e.worker == nil {
+		return nil, fmt.Errorf("rbe: worker not reserved, SetUp must be called first")
+	}
+	return url.Parse(e.worker.WebDriverURL)
+}
+
+// TearDown releases the reserved worker back to the scheduler.
+func (e *env) TearDown(ctx context.Context) error {
+	if e.worker == nil {
+		return nil
+	}
+	return e.client.Release(ctx, e.worker)
+}
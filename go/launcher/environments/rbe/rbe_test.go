@@ -0,0 +1,46 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbe
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewRequiresEndpointFlag(t *testing.T) {
+	old := *endpointFlag
+	*endpointFlag = ""
+	defer func() { *endpointFlag = old }()
+
+	if _, err := New(nil, nil); err == nil {
+		t.Errorf("New() with no --rbe_endpoint returned nil error, want non-nil")
+	}
+}
+
+func TestWDAddressBeforeSetUpReturnsError(t *testing.T) {
+	e := &env{}
+
+	if _, err := e.WDAddress(context.Background()); err == nil {
+		t.Errorf("WDAddress() before SetUp returned nil error, want non-nil")
+	}
+}
+
+func TestTearDownBeforeSetUpIsNoOp(t *testing.T) {
+	e := &env{}
+
+	if err := e.TearDown(context.Background()); err != nil {
+		t.Errorf("TearDown() before SetUp returned error %v, want nil", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// schedulerScope is the OAuth2 scope requested for the token minted from
+// the service account key when talking to the scheduler.
+const schedulerScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// worker describes a browser worker reserved from the remote scheduler.
+type worker struct {
+	ID           string `json:"id"`
+	WebDriverURL string `json:"webDriverUrl"`
+}
+
+// schedulerClient talks to the RBE scheduler's reservation API.
+type schedulerClient struct {
+	endpoint string
+	// tokenSource is nil when no credential file was configured, in which
+	// case requests are sent unauthenticated.
+	tokenSource oauth2.TokenSource
+	http        *http.Client
+}
+
+func newSchedulerClient(endpoint, credentialFile string) (*schedulerClient, error) {
+	var ts oauth2.TokenSource
+	if credentialFile != "" {
+		keyJSON, err := ioutil.ReadFile(credentialFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read service account key %q: %v", credentialFile, err)
+		}
+		cfg, err := google.JWTConfigFromJSON(keyJSON, schedulerScope)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse service account key %q: %v", credentialFile, err)
+		}
+		ts = cfg.TokenSource(context.Background())
+	}
+	return &schedulerClient{
+		endpoint:    endpoint,
+		tokenSource: ts,
+		http:        &http.Client{},
+	}, nil
+}
+
+// Reserve asks the scheduler for a worker, optionally constrained to platform.
+func (c *schedulerClient) Reserve(ctx context.Context, platform string) (*worker, error) {
+	reqBody, err := json.Marshal(struct {
+		Platform string `json:"platform,omitempty"`
+	}{Platform: platform})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+"/v1/workers:reserve", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if err := c.authenticate(req); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scheduler returned status %s", resp.Status)
+	}
+
+	var w worker
+	if err := json.NewDecoder(resp.Body).Decode(&w); err != nil {
+		return nil, fmt.Errorf("unable to decode scheduler response: %v", err)
+	}
+	return &w, nil
+}
+
+// Release returns a previously reserved worker to the pool.
+func (c *schedulerClient) Release(ctx context.Context, w *worker) error {
+	req, err := http.NewRequest(http.MethodPost, c.endpoint+"/v1/workers/"+w.ID+":release", nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if err := c.authenticate(req); err != nil {
+		return err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("scheduler returned status %s releasing worker %q", resp.Status, w.ID)
+	}
+	return nil
+}
+
+// authenticate mints an access token from the configured service account
+// key, if any, and attaches it to req as a bearer token.
+func (c *schedulerClient) authenticate(req *http.Request) error {
+	if c.tokenSource == nil {
+		return nil
+	}
+	tok, err := c.tokenSource.Token()
+	if err != nil {
+		return fmt.Errorf("unable to mint access token: %v", err)
+	}
+	tok.SetAuthHeader(req)
+	return nil
+}
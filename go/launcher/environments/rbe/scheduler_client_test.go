@@ -0,0 +1,86 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func TestAuthenticateWithoutCredentialIsNoOp(t *testing.T) {
+	c := &schedulerClient{http: &http.Client{}}
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() returned error: %v", err)
+	}
+
+	if err := c.authenticate(req); err != nil {
+		t.Fatalf("authenticate() returned error: %v", err)
+	}
+	if got := req.Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization header = %q, want empty", got)
+	}
+}
+
+func TestAuthenticateSetsBearerToken(t *testing.T) {
+	c := &schedulerClient{
+		http:        &http.Client{},
+		tokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "fake-token"}),
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() returned error: %v", err)
+	}
+
+	if err := c.authenticate(req); err != nil {
+		t.Fatalf("authenticate() returned error: %v", err)
+	}
+	if got, want := req.Header.Get("Authorization"), "Bearer fake-token"; got != want {
+		t.Errorf("Authorization header = %q, want %q", got, want)
+	}
+}
+
+func TestReserveAndRelease(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/workers:reserve":
+			json.NewEncoder(w).Encode(worker{ID: "w1", WebDriverURL: "http://worker/wd/hub"})
+		case "/v1/workers/w1:release":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := &schedulerClient{endpoint: srv.URL, http: &http.Client{}}
+
+	w, err := c.Reserve(context.Background(), "linux")
+	if err != nil {
+		t.Fatalf("Reserve() returned error: %v", err)
+	}
+	if w.ID != "w1" {
+		t.Errorf("Reserve() worker ID = %q, want %q", w.ID, "w1")
+	}
+
+	if err := c.Release(context.Background(), w); err != nil {
+		t.Errorf("Release() returned error: %v", err)
+	}
+}
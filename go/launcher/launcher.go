@@ -19,35 +19,93 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/bazelbuild/rules_webtesting/go/launcher/cmdhelper"
+	"github.com/bazelbuild/rules_webtesting/go/launcher/companions"
 	"github.com/bazelbuild/rules_webtesting/go/launcher/diagnostics"
+	"github.com/bazelbuild/rules_webtesting/go/launcher/diagnostics/steplog"
 	"github.com/bazelbuild/rules_webtesting/go/launcher/environments/environment"
+	"github.com/bazelbuild/rules_webtesting/go/launcher/environments/rbe"
 	"github.com/bazelbuild/rules_webtesting/go/launcher/proxy/proxy"
+	"github.com/bazelbuild/rules_webtesting/go/launcher/sandbox"
+	"github.com/bazelbuild/rules_webtesting/go/launcher/script"
 	"github.com/bazelbuild/rules_webtesting/go/metadata/metadata"
 	"github.com/bazelbuild/rules_webtesting/go/util/bazel"
 )
 
 type envProvider func(m *metadata.Metadata, d diagnostics.Diagnostics) (environment.Env, error)
 
+// companionFlag collects repeated --companion flags of the form
+// exe=ready_file into a list of companions.Spec.
+type companionFlag []companions.Spec
+
+func (c *companionFlag) String() string {
+	return fmt.Sprintf("%v", []companions.Spec(*c))
+}
+
+func (c *companionFlag) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("--companion must be of the form exe=ready_file, got %q", value)
+	}
+	*c = append(*c, companions.Spec{Exe: parts[0], ReadyFile: parts[1]})
+	return nil
+}
+
+// companionReadyTimeout bounds how long Run waits for companion binaries
+// to signal readiness before giving up.
+const companionReadyTimeout = 30 * time.Second
+
+// defaultGracePeriod is how long Run waits for the test process to exit
+// after being sent SIGTERM before escalating to SIGKILL.
+const defaultGracePeriod = 100 * time.Millisecond
+
 var (
 	test             = flag.String("test", "", "Test script to launch")
+	scriptFlag       = flag.String("script", "", "if set, run this txtar-format script against the proxy instead of launching --test")
 	metadataFileFlag = flag.String("metadata", "", "metadata file")
+	sandboxTest      = flag.Bool("sandbox", false, "run the test in an isolated, per-test working directory")
+	portFile         = flag.String("port_file", "", "if set, the proxy address is written to this file once it is ready to accept connections")
+	envReadyFile     = flag.String("env_ready_file", "", "alias for --port_file")
+	stepLogFile      = flag.String("step_log", "", "if set, write a JSON stream of lifecycle events to this path")
+	gracePeriodFlag  = flag.Duration("grace_period", defaultGracePeriod, "how long to wait for the test process to exit after SIGTERM before sending SIGKILL")
+	companionFlags   companionFlag
 	envProviders     = map[string]envProvider{}
 )
 
+func init() {
+	flag.Var(&companionFlags, "companion", "a companion binary to run alongside the test, as exe=ready_file; may be repeated")
+}
+
 func main() {
 	flag.Parse()
 
 	d := diagnostics.NoOP()
 
-	status := Run(d)
+	var sl *steplog.Logger
+	if *stepLogFile != "" {
+		var err error
+		sl, err = steplog.New(*stepLogFile)
+		if err != nil {
+			log.Fatalf("Error creating step log: %v", err)
+		}
+	}
+
+	status := Run(d, sl)
 
 	d.Close()
+	if sl != nil {
+		sl.Close()
+	}
 	os.Exit(status)
 }
 
@@ -56,8 +114,104 @@ func RegisterEnvProviderFunc(name string, p envProvider) {
 	envProviders[name] = p
 }
 
-// Run runs the test.
-func Run(d diagnostics.Diagnostics) int {
+func init() {
+	// rbe provisions browsers from a Remote Build Execution style grid
+	// instead of starting them as local processes.
+	RegisterEnvProviderFunc("rbe", rbe.New)
+}
+
+// gracePeriod returns the configured grace period, scaled down to a
+// fraction of Bazel's TEST_TIMEOUT when that would otherwise leave too
+// little time for teardown before Bazel kills the launcher itself.
+func gracePeriod() time.Duration {
+	gp := *gracePeriodFlag
+	raw := os.Getenv("TEST_TIMEOUT")
+	if raw == "" {
+		return gp
+	}
+	secs, err := strconv.Atoi(raw)
+	if err != nil {
+		return gp
+	}
+	if scaled := time.Duration(secs) * time.Second / 10; scaled < gp {
+		return scaled
+	}
+	return gp
+}
+
+// runChild starts cmd and waits for it to exit, forwarding ctx's
+// cancellation (set up by Run to trigger on SIGINT/SIGTERM) to cmd as
+// SIGTERM, then escalating to SIGKILL if cmd has not exited within grace of
+// that.
+func runChild(ctx context.Context, cmd *exec.Cmd, grace time.Duration) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		log.Printf("%v, forwarding SIGTERM to test and waiting up to %v for it to exit", ctx.Err(), grace)
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(grace):
+			log.Printf("Test did not exit within grace period, sending SIGKILL")
+			cmd.Process.Kill()
+			return <-done
+		}
+	}
+}
+
+// Run runs the test. sl is nil unless --step_log was set; it is kept
+// separate from d rather than folded into the diagnostics.Diagnostics
+// value, since diagnostics.Diagnostics isn't guaranteed to be satisfied by
+// *steplog.Logger's broader Step/Event API.
+func Run(d diagnostics.Diagnostics, sl *steplog.Logger) (status int) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic in Run: %v", r)
+			status = 1
+		}
+	}()
+
+	// ctx is canceled as soon as the launcher receives SIGINT/SIGTERM, so
+	// that a slow env.SetUp/p.Start is interrupted instead of leaving the
+	// process to be killed outright before env.TearDown/p.Shutdown (below)
+	// get a chance to run.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			log.Printf("Received %v, shutting down", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// step and event are no-ops unless --step_log is set.
+	step := func(stepID, message string) func() {
+		if sl == nil {
+			return func() {}
+		}
+		return sl.Step(stepID, message)
+	}
+	event := func(stepID, message string) {
+		if sl != nil {
+			sl.Event(stepID, message)
+		}
+	}
+
 	metadataFile, err := bazel.Runfile(*metadataFileFlag)
 	if err != nil {
 		log.Printf("Error locating metadata file: %v", err)
@@ -76,15 +230,19 @@ func Run(d diagnostics.Diagnostics) int {
 		return 127
 	}
 
-	if err := env.SetUp(context.Background()); err != nil {
+	endEnvSetUp := step("env_setup", "environment setup")
+	if err := env.SetUp(ctx); err != nil {
 		log.Printf("Error setting up environment: %v", err)
 		return 127
 	}
+	endEnvSetUp()
 
 	defer func() {
+		endTearDown := step("env_teardown", "environment teardown")
 		if err := env.TearDown(context.Background()); err != nil {
 			log.Printf("Error tearing down environment: %v", err)
 		}
+		endTearDown()
 	}()
 
 	p, err := proxy.New(env, m, d)
@@ -93,10 +251,47 @@ func Run(d diagnostics.Diagnostics) int {
 		return 127
 	}
 
-	if err := p.Start(context.Background()); err != nil {
+	if err := p.Start(ctx); err != nil {
 		log.Printf("Error starting proxy: %v", err)
 		return 127
 	}
+	event("proxy_start", fmt.Sprintf("proxy listening at %s", p.Address))
+
+	defer func() {
+		if err := p.Shutdown(context.Background()); err != nil {
+			log.Printf("Error shutting down proxy: %v", err)
+		}
+	}()
+
+	readyFile := *portFile
+	if readyFile == "" {
+		readyFile = *envReadyFile
+	}
+	if readyFile != "" {
+		if err := companions.WriteReadyFile(readyFile, p.Address); err != nil {
+			log.Printf("Error writing %s: %v", readyFile, err)
+			return 127
+		}
+	}
+
+	var startedCompanions []*companions.Companion
+	if len(companionFlags) > 0 {
+		startedCompanions, err = companions.Start(companionFlags)
+		if err != nil {
+			log.Printf("Error starting companions: %v", err)
+			return 127
+		}
+		defer companions.Stop(startedCompanions)
+
+		if err := companions.WaitReady(startedCompanions, companionReadyTimeout); err != nil {
+			log.Printf("Error waiting for companions: %v", err)
+			return 127
+		}
+	}
+
+	if *scriptFlag != "" {
+		return runScript(*scriptFlag, p.Address)
+	}
 
 	testExe, err := bazel.Runfile(*test)
 	if err != nil {
@@ -122,13 +317,67 @@ func Run(d diagnostics.Diagnostics) int {
 	testCmd.Stderr = os.Stderr
 	testCmd.Stdin = os.Stdin
 
-	if status := testCmd.Run(); status != nil {
-		log.Printf("test failed %v", status)
-		if ee, ok := err.(*exec.ExitError); ok {
+	if *sandboxTest {
+		sb, err := sandbox.New(bazel.RunfilesDir())
+		if err != nil {
+			log.Printf("Error building test sandbox: %v", err)
+			return 127
+		}
+		defer func() {
+			if err := sb.TearDown(); err != nil {
+				log.Printf("Error tearing down test sandbox: %v", err)
+			}
+		}()
+
+		testCmd.Dir = sb.Root
+		testCmd.Env = cmdhelper.BulkUpdateEnv(testCmd.Env, map[string]string{
+			"TEST_TMPDIR":     sb.Scratch,
+			"WEB_TEST_TMPDIR": sb.Scratch,
+		})
+	}
+
+	endTest := step("test", fmt.Sprintf("running %s", *test))
+	testErr := runChild(ctx, testCmd, gracePeriod())
+	endTest()
+	if testErr != nil {
+		log.Printf("test failed %v", testErr)
+		if ee, ok := testErr.(*exec.ExitError); ok {
 			if ws, ok := ee.Sys().(syscall.WaitStatus); ok {
+				event("test_exit", fmt.Sprintf("exit code %d", ws.ExitStatus()))
 				return ws.ExitStatus()
 			}
 		}
+		event("test_exit", "exit code 1")
+		return 1
+	}
+	event("test_exit", "exit code 0")
+	return 0
+}
+
+// runScript reads and executes a txtar-format test script against the
+// proxy listening at proxyAddr, in lieu of exec'ing a --test binary.
+func runScript(scriptFile, proxyAddr string) int {
+	scriptPath, err := bazel.Runfile(scriptFile)
+	if err != nil {
+		log.Printf("unable to find %s", scriptFile)
+		return 127
+	}
+
+	data, err := ioutil.ReadFile(scriptPath)
+	if err != nil {
+		log.Printf("Error reading script %s: %v", scriptPath, err)
+		return 127
+	}
+
+	steps, err := script.Parse(data)
+	if err != nil {
+		log.Printf("Error parsing script %s: %v", scriptPath, err)
+		return 127
+	}
+
+	r := script.NewRunner(fmt.Sprintf("http://%s/wd/hub", proxyAddr))
+	if err := script.Run(r, steps); err != nil {
+		log.Printf("Script failed: %v", err)
 		return 1
 	}
 	return 0
@@ -0,0 +1,50 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGracePeriodDefaultsToFlag(t *testing.T) {
+	os.Unsetenv("TEST_TIMEOUT")
+	*gracePeriodFlag = 5 * time.Second
+
+	if got, want := gracePeriod(), 5*time.Second; got != want {
+		t.Errorf("gracePeriod() = %v, want %v", got, want)
+	}
+}
+
+func TestGracePeriodScalesDownForShortTestTimeout(t *testing.T) {
+	*gracePeriodFlag = 5 * time.Second
+	os.Setenv("TEST_TIMEOUT", "10")
+	defer os.Unsetenv("TEST_TIMEOUT")
+
+	if got, want := gracePeriod(), 1*time.Second; got != want {
+		t.Errorf("gracePeriod() = %v, want %v", got, want)
+	}
+}
+
+func TestGracePeriodIgnoresMalformedTestTimeout(t *testing.T) {
+	*gracePeriodFlag = 5 * time.Second
+	os.Setenv("TEST_TIMEOUT", "not-a-number")
+	defer os.Unsetenv("TEST_TIMEOUT")
+
+	if got, want := gracePeriod(), 5*time.Second; got != want {
+		t.Errorf("gracePeriod() = %v, want %v", got, want)
+	}
+}
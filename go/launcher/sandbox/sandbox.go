@@ -0,0 +1,127 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sandbox symlinks only the runfiles a test declared as data
+// dependencies into a fresh, per-invocation root, instead of chdir'ing the
+// test into the shared runfiles tree. This keeps a test that writes into
+// its own working directory from corrupting a sibling invocation's view of
+// the same runfiles.
+package sandbox
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox is a synthetic root directory containing a symlink farm of the
+// declared runfiles plus a writable scratch directory for tmp files.
+type Sandbox struct {
+	// Root is the directory the test binary should be chdir'd into.
+	Root string
+	// Scratch is a writable directory under Root, suitable for
+	// TEST_TMPDIR/WEB_TEST_TMPDIR.
+	Scratch string
+}
+
+// New creates a new Sandbox under a fresh temp directory, symlinking only
+// the runfiles this binary declared as inputs, preserving their paths
+// relative to runfilesDir, so the test sees an access-restricted view
+// rather than a relocated copy of the whole runfiles tree. The declared set
+// is read from the Bazel runfiles manifest, so New reflects exactly what
+// Bazel resolved for this binary rather than a separately maintained list.
+func New(runfilesDir string) (*Sandbox, error) {
+	root, err := ioutil.TempDir("", "web_test_sandbox")
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: unable to create root: %v", err)
+	}
+
+	declared, err := declaredRunfiles(runfilesDir)
+	if err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	if err := farm(runfilesDir, root, declared); err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+
+	scratch := filepath.Join(root, "tmp")
+	if err := os.Mkdir(scratch, 0755); err != nil {
+		os.RemoveAll(root)
+		return nil, fmt.Errorf("sandbox: unable to create scratch dir: %v", err)
+	}
+
+	return &Sandbox{Root: root, Scratch: scratch}, nil
+}
+
+// declaredRunfiles returns the runfiles paths (relative to runfilesDir)
+// that Bazel resolved for this binary, read from the runfiles manifest
+// file Bazel writes alongside the runfiles directory. Each manifest line
+// is "shortpath realpath"; declaredRunfiles returns the shortpaths.
+func declaredRunfiles(runfilesDir string) ([]string, error) {
+	manifest := os.Getenv("RUNFILES_MANIFEST_FILE")
+	if manifest == "" {
+		manifest = runfilesDir + "_manifest"
+	}
+
+	f, err := os.Open(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox: unable to open runfiles manifest %q: %v", manifest, err)
+	}
+	defer f.Close()
+
+	var declared []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		shortpath := strings.SplitN(line, " ", 2)[0]
+		declared = append(declared, shortpath)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("sandbox: unable to read runfiles manifest %q: %v", manifest, err)
+	}
+	return declared, nil
+}
+
+// TearDown removes the sandbox and everything symlinked or written under
+// it. It does not touch the original runfiles.
+func (s *Sandbox) TearDown() error {
+	return os.RemoveAll(s.Root)
+}
+
+// farm symlinks each of declared (paths relative to src) into the
+// equivalent path under dst, creating parent directories as needed, so dst
+// ends up containing only the inputs the test actually declared.
+func farm(src, dst string, declared []string) error {
+	for _, rel := range declared {
+		oldname := filepath.Join(src, rel)
+		newname := filepath.Join(dst, rel)
+
+		if err := os.MkdirAll(filepath.Dir(newname), 0755); err != nil {
+			return fmt.Errorf("sandbox: unable to create directory for %q: %v", rel, err)
+		}
+		if err := os.Symlink(oldname, newname); err != nil {
+			return fmt.Errorf("sandbox: unable to link %q into sandbox: %v", rel, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sandbox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFarmOnlyLinksDeclaredRunfiles(t *testing.T) {
+	src, err := ioutil.TempDir("", "sandbox_src")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "sandbox_dst")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dst)
+
+	if err := os.MkdirAll(filepath.Join(src, "data"), 0755); err != nil {
+		t.Fatalf("MkdirAll() returned error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "data", "declared.txt"), []byte("declared"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "undeclared.txt"), []byte("undeclared"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+
+	if err := farm(src, dst, []string{"data/declared.txt"}); err != nil {
+		t.Fatalf("farm() returned error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dst, "data", "declared.txt")); err != nil {
+		t.Errorf("declared runfile was not linked into the sandbox: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "undeclared.txt")); !os.IsNotExist(err) {
+		t.Errorf("undeclared runfile was linked into the sandbox, want it absent")
+	}
+}
+
+func TestFarmFailsWhenParentPathIsBlockedByAFile(t *testing.T) {
+	src, err := ioutil.TempDir("", "sandbox_src")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(src)
+
+	dst, err := ioutil.TempDir("", "sandbox_dst")
+	if err != nil {
+		t.Fatalf("TempDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dst)
+
+	// "data" already exists in dst as a regular file, so MkdirAll'ing
+	// "data/declared.txt"'s parent directory must fail.
+	if err := ioutil.WriteFile(filepath.Join(dst, "data"), []byte("blocker"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+
+	if err := farm(src, dst, []string{"data/declared.txt"}); err == nil {
+		t.Errorf("farm() with a blocked parent directory returned nil error, want non-nil")
+	}
+}
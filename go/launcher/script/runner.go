@@ -0,0 +1,181 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+)
+
+// Runner drives a WebDriver endpoint and the local shell on behalf of a
+// script's steps, and remembers enough state (the current session, the last
+// exec output) for later steps to refer back to.
+type Runner struct {
+	// WebDriverAddr is the base URL of the proxy's WebDriver endpoint,
+	// e.g. "http://127.0.0.1:1234/wd/hub".
+	WebDriverAddr string
+
+	client    *http.Client
+	sessionID string
+	lastOut   string
+}
+
+// NewRunner creates a Runner that drives the WebDriver endpoint at addr.
+func NewRunner(addr string) *Runner {
+	return &Runner{WebDriverAddr: addr, client: &http.Client{}}
+}
+
+// checkStatus returns an error describing resp's body if resp did not
+// succeed, so a broken WebDriver call fails the step instead of being
+// silently treated as success.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	return fmt.Errorf("got status %s: %s", resp.Status, body)
+}
+
+func (r *Runner) step(s Step) error {
+	switch s.Cmd {
+	case "browser":
+		if len(s.Args) != 1 || s.Args[0] != "open" {
+			return fmt.Errorf("usage: browser open")
+		}
+		return r.openSession()
+	case "nav":
+		if len(s.Args) != 1 {
+			return fmt.Errorf("usage: nav <url>")
+		}
+		return r.navigate(s.Args[0])
+	case "exec":
+		if len(s.Args) == 0 {
+			return fmt.Errorf("usage: exec <cmd> [args...]")
+		}
+		return r.shellExec(s.Args)
+	case "expect":
+		if len(s.Args) != 1 {
+			return fmt.Errorf("usage: expect <regex>")
+		}
+		ok, err := matches(s.Args[0], r.lastOut)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("output %q did not match %q", r.lastOut, s.Args[0])
+		}
+		return nil
+	case "screenshot":
+		if len(s.Args) != 1 {
+			return fmt.Errorf("usage: screenshot <name>")
+		}
+		return r.screenshot(s.Args[0])
+	default:
+		return fmt.Errorf("unknown step %q", s.Cmd)
+	}
+}
+
+func (r *Runner) openSession() error {
+	body, err := json.Marshal(map[string]interface{}{
+		"capabilities": map[string]interface{}{},
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Post(r.WebDriverAddr+"/session", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to open session: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("unable to open session: %v", err)
+	}
+
+	var result struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("unable to decode session response: %v", err)
+	}
+	r.sessionID = result.SessionID
+	return nil
+}
+
+func (r *Runner) navigate(url string) error {
+	if r.sessionID == "" {
+		return fmt.Errorf("no open session, run \"browser open\" first")
+	}
+	body, err := json.Marshal(map[string]string{"url": url})
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Post(r.WebDriverAddr+"/session/"+r.sessionID+"/url", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to navigate to %q: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("unable to navigate to %q: %v", url, err)
+	}
+	return nil
+}
+
+func (r *Runner) shellExec(args []string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	out, err := cmd.CombinedOutput()
+	r.lastOut = string(out)
+	if err != nil {
+		return fmt.Errorf("command failed: %v: %s", err, out)
+	}
+	return nil
+}
+
+func (r *Runner) screenshot(name string) error {
+	if r.sessionID == "" {
+		return fmt.Errorf("no open session, run \"browser open\" first")
+	}
+	resp, err := r.client.Get(r.WebDriverAddr + "/session/" + r.sessionID + "/screenshot")
+	if err != nil {
+		return fmt.Errorf("unable to take screenshot: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		return fmt.Errorf("unable to take screenshot: %v", err)
+	}
+
+	var result struct {
+		Value string `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("unable to decode screenshot response: %v", err)
+	}
+
+	png, err := base64.StdEncoding.DecodeString(result.Value)
+	if err != nil {
+		return fmt.Errorf("unable to decode screenshot data: %v", err)
+	}
+	return ioutil.WriteFile(name+".png", png, os.FileMode(0644))
+}
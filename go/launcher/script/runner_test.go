@@ -0,0 +1,60 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenSessionFailsOnNonOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("boom"))
+	}))
+	defer srv.Close()
+
+	r := NewRunner(srv.URL)
+	if err := r.openSession(); err == nil {
+		t.Errorf("openSession() against a failing server returned nil error, want non-nil")
+	}
+}
+
+func TestNavigateFailsOnNonOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	r := NewRunner(srv.URL)
+	r.sessionID = "fake-session"
+	if err := r.navigate("https://example.com"); err == nil {
+		t.Errorf("navigate() against a failing server returned nil error, want non-nil")
+	}
+}
+
+func TestNavigateSucceedsOnOKStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	r := NewRunner(srv.URL)
+	r.sessionID = "fake-session"
+	if err := r.navigate("https://example.com"); err != nil {
+		t.Errorf("navigate() returned error: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package script interprets a small txtar-based scripting language that
+// drives a running WebDriver proxy without requiring the author to write a
+// Go test binary.
+//
+// The archive's top comment holds one step per line:
+//
+//	browser open
+//	nav https://example.com
+//	exec echo hello
+//	expect hello
+//	screenshot landing
+//	stop
+//
+// Any files included in the archive are ignored by the runner; they exist
+// so authors can keep fixtures alongside the script.
+package script
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/txtar"
+)
+
+// Step is a single parsed instruction from a script.
+type Step struct {
+	// Cmd is the first word of the line, e.g. "nav" or "expect".
+	Cmd string
+	// Args are the remaining, whitespace-split words on the line.
+	Args []string
+}
+
+// Parse reads a txtar-formatted script and returns its steps in order.
+func Parse(data []byte) ([]Step, error) {
+	ar := txtar.Parse(data)
+
+	var steps []Step
+	scanner := bufio.NewScanner(strings.NewReader(string(ar.Comment)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var args []string
+		if len(fields) > 1 {
+			args = fields[1:]
+		}
+		steps = append(steps, Step{Cmd: fields[0], Args: args})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("script: unable to parse steps: %v", err)
+	}
+	return steps, nil
+}
+
+// Run executes steps in order against r, stopping and returning an error on
+// the first step that fails, or nil once a "stop" step is reached or the
+// steps are exhausted.
+func Run(r *Runner, steps []Step) error {
+	for i, s := range steps {
+		if s.Cmd == "stop" {
+			return nil
+		}
+		if err := r.step(s); err != nil {
+			return fmt.Errorf("script: step %d (%s): %v", i+1, s.Cmd, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether text matches the regular expression pattern.
+func matches(pattern, text string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, fmt.Errorf("invalid expect pattern %q: %v", pattern, err)
+	}
+	return re.MatchString(text), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`browser open
+nav https://example.com
+exec echo hello
+expect hello
+screenshot landing
+stop
+`)
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	want := []Step{
+		{Cmd: "browser", Args: []string{"open"}},
+		{Cmd: "nav", Args: []string{"https://example.com"}},
+		{Cmd: "exec", Args: []string{"echo", "hello"}},
+		{Cmd: "expect", Args: []string{"hello"}},
+		{Cmd: "screenshot", Args: []string{"landing"}},
+		{Cmd: "stop", Args: nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseSkipsBlankLinesAndComments(t *testing.T) {
+	data := []byte(`# a comment
+
+nav https://example.com
+`)
+
+	got, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	want := []Step{{Cmd: "nav", Args: []string{"https://example.com"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	ok, err := matches("hel+o", "hello world")
+	if err != nil {
+		t.Fatalf("matches() returned error: %v", err)
+	}
+	if !ok {
+		t.Errorf("matches(\"hel+o\", \"hello world\") = false, want true")
+	}
+
+	ok, err = matches("goodbye", "hello world")
+	if err != nil {
+		t.Fatalf("matches() returned error: %v", err)
+	}
+	if ok {
+		t.Errorf("matches(\"goodbye\", \"hello world\") = true, want false")
+	}
+}
+
+func TestMatchesInvalidPattern(t *testing.T) {
+	if _, err := matches("(", "hello"); err == nil {
+		t.Errorf("matches() with invalid pattern returned nil error, want non-nil")
+	}
+}